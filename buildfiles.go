@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// splitGroupArtifact derives a Maven/Gradle-style (groupId, artifactId)
+// pair from a dotted package name, treating the last segment as the
+// artifact and everything before it as the group - the same heuristic the
+// original blind string-replace implementation used, now applied only to
+// structurally-identified coordinate fields instead of the whole file.
+func splitGroupArtifact(name string) (group, artifact string) {
+	parts := splitDotted(name)
+	artifact = parts[len(parts)-1]
+	group = strings.Join(parts[:len(parts)-1], ".")
+	return group, artifact
+}
+
+// matchesGroup reports whether text is this rename's group coordinate:
+// either the whole old name (the common case, where a groupId/gradle
+// `group` is simply the project's base package) or the heuristic group
+// derived by dropping oldName's last segment.
+func matchesGroup(text, oldFull, oldGroup string) bool {
+	return text == oldFull || (oldGroup != "" && text == oldGroup)
+}
+
+// groupReplacement returns what matchesGroup's match should become.
+func groupReplacement(text, oldFull, newFull, newGroup string) string {
+	if text == oldFull {
+		return newFull
+	}
+	return newGroup
+}
+
+// applySpans rewrites content at the given byte ranges, from the end of
+// the file backward so earlier offsets stay valid, mirroring rewriteDecls.
+// Callers may discover spans in any order (e.g. attribute matches within a
+// tag), so spans are sorted by from before being applied - splicing out of
+// order would corrupt the output.
+func applySpans(content []byte, spans []struct {
+	from, to int
+	text     string
+}) []byte {
+	spans = append([]struct {
+		from, to int
+		text     string
+	}(nil), spans...)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].from < spans[j].from })
+
+	out := append([]byte(nil), content...)
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		tail := append([]byte(nil), out[s.to:]...)
+		out = append(out[:s.from], append([]byte(s.text), tail...)...)
+	}
+	return out
+}
+
+// computePomEdit rewrites a pom.xml's own <project> <groupId> and
+// <artifactId>, plus any <dependency> whose <groupId> and <artifactId>
+// exactly match the project's own former coordinate (an internal module
+// referencing its renamed sibling/parent), without touching unrelated
+// <name>, <description>, <packaging> (which holds a packaging type like
+// "jar" or "pom", never the artifact id), or third-party dependencies that
+// merely share a substring.
+func computePomEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	oldGroup, oldArtifact := splitGroupArtifact(oldName)
+	newGroup, newArtifact := splitGroupArtifact(newName)
+
+	type coordRef struct {
+		groupSpan, artifactSpan [2]int
+		group, artifact         string
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	var stack []string
+	var cur *coordRef
+	curDepth := 0
+	var deps []*coordRef
+	root := coordRef{}
+
+	var pendingSpan *[2]int
+	var pendingText *string
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return FileEdit{}, false, fmt.Errorf("parsing %s: %w", path, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if t.Name.Local == "dependency" {
+				cur = &coordRef{}
+				curDepth = len(stack)
+				deps = append(deps, cur)
+			}
+
+			pendingSpan, pendingText = nil, nil
+			switch {
+			case len(stack) == 2 && stack[0] == "project" && t.Name.Local == "groupId":
+				pendingSpan, pendingText = &root.groupSpan, &root.group
+			case len(stack) == 2 && stack[0] == "project" && t.Name.Local == "artifactId":
+				pendingSpan, pendingText = &root.artifactSpan, &root.artifact
+			case cur != nil && len(stack) == curDepth+1 && t.Name.Local == "groupId":
+				pendingSpan, pendingText = &cur.groupSpan, &cur.group
+			case cur != nil && len(stack) == curDepth+1 && t.Name.Local == "artifactId":
+				pendingSpan, pendingText = &cur.artifactSpan, &cur.artifact
+			}
+
+		case xml.CharData:
+			if pendingSpan != nil {
+				end := dec.InputOffset()
+				start := end - int64(len(t))
+				*pendingSpan = [2]int{int(start), int(end)}
+				*pendingText = strings.TrimSpace(string(t))
+				pendingSpan, pendingText = nil, nil
+			}
+
+		case xml.EndElement:
+			if cur != nil && len(stack) == curDepth {
+				cur = nil
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			pendingSpan, pendingText = nil, nil
+		}
+	}
+
+	type span struct {
+		from, to int
+		text     string
+	}
+	var spans []span
+
+	if matchesGroup(root.group, oldName, oldGroup) {
+		spans = append(spans, span{root.groupSpan[0], root.groupSpan[1], groupReplacement(root.group, oldName, newName, newGroup)})
+	}
+	if root.artifact == oldArtifact {
+		spans = append(spans, span{root.artifactSpan[0], root.artifactSpan[1], newArtifact})
+	}
+	for _, d := range deps {
+		if matchesGroup(d.group, oldName, oldGroup) && d.artifact == oldArtifact {
+			spans = append(spans, span{d.groupSpan[0], d.groupSpan[1], groupReplacement(d.group, oldName, newName, newGroup)})
+			spans = append(spans, span{d.artifactSpan[0], d.artifactSpan[1], newArtifact})
+		}
+	}
+
+	if len(spans) == 0 {
+		return FileEdit{}, false, nil
+	}
+
+	genericSpans := make([]struct {
+		from, to int
+		text     string
+	}, len(spans))
+	for i, s := range spans {
+		genericSpans[i] = s
+	}
+	return FileEdit{Path: path, NewContent: applySpans(content, genericSpans)}, true, nil
+}
+
+var (
+	gradleGroupRe     = regexp.MustCompile(`^(\s*group\s*=?\s*)(['"])([^'"]*)(['"])\s*$`)
+	gradleRootNameRe  = regexp.MustCompile(`^(\s*rootProject\.name\s*=?\s*)(['"])([^'"]*)(['"])\s*$`)
+	gradleArchivesRe  = regexp.MustCompile(`^(\s*archivesBaseName\s*=?\s*)(['"])([^'"]*)(['"])\s*$`)
+	gradleIncludeRe   = regexp.MustCompile(`^\s*include\b`)
+	gradleQuotedTokRe = regexp.MustCompile(`(['"])([^'"]*)(['"])`)
+)
+
+// computeGradleEdit rewrites the top-level `group`, `rootProject.name`, and
+// `archivesBaseName` assignments in a build.gradle/build.gradle.kts, or the
+// `rootProject.name` and `include(...)` module paths in a settings.gradle,
+// scanning depth-by-line so an identically-named `group:` keyword argument
+// inside a nested `dependencies { ... }` block is left alone.
+func computeGradleEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	oldGroup, oldArtifact := splitGroupArtifact(oldName)
+	newGroup, newArtifact := splitGroupArtifact(newName)
+
+	lines := splitLinesKeepEOL(content)
+	depths := topLevelDepths(content, lines)
+
+	var out bytes.Buffer
+	changed := false
+
+	for i, line := range lines {
+		if depths[i] != 0 {
+			out.WriteString(line)
+			continue
+		}
+
+		text, body := splitEOL(line)
+		switch {
+		case gradleGroupRe.MatchString(text):
+			m := gradleGroupRe.FindStringSubmatch(text)
+			if matchesGroup(m[3], oldName, oldGroup) {
+				text = m[1] + m[2] + groupReplacement(m[3], oldName, newName, newGroup) + m[4]
+				changed = true
+			}
+		case gradleRootNameRe.MatchString(text):
+			m := gradleRootNameRe.FindStringSubmatch(text)
+			if m[3] == oldArtifact {
+				text = m[1] + m[2] + newArtifact + m[4]
+				changed = true
+			}
+		case gradleArchivesRe.MatchString(text):
+			m := gradleArchivesRe.FindStringSubmatch(text)
+			if m[3] == oldArtifact {
+				text = m[1] + m[2] + newArtifact + m[4]
+				changed = true
+			}
+		case gradleIncludeRe.MatchString(text):
+			rewritten := gradleQuotedTokRe.ReplaceAllStringFunc(text, func(tok string) string {
+				m := gradleQuotedTokRe.FindStringSubmatch(tok)
+				if m[2] == ":"+oldArtifact {
+					changed = true
+					return m[1] + ":" + newArtifact + m[3]
+				}
+				return tok
+			})
+			text = rewritten
+		}
+
+		out.WriteString(text)
+		out.WriteString(body)
+	}
+
+	if !changed {
+		return FileEdit{}, false, nil
+	}
+	return FileEdit{Path: path, NewContent: out.Bytes()}, true, nil
+}
+
+// splitLinesKeepEOL splits content into lines, each retaining its trailing
+// "\n" (or "\r\n"), so rejoining the slice reproduces the original bytes.
+func splitLinesKeepEOL(content []byte) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, string(content[start:i+1]))
+			start = i + 1
+		}
+	}
+	if start < len(content) {
+		lines = append(lines, string(content[start:]))
+	}
+	return lines
+}
+
+// splitEOL separates a line's content from its trailing line-ending bytes.
+func splitEOL(line string) (text, eol string) {
+	trimmed := strings.TrimRight(line, "\n")
+	text = strings.TrimRight(trimmed, "\r")
+	return text, line[len(text):]
+}
+
+// topLevelDepths returns, for each line, the brace depth in effect at the
+// start of that line - skipping braces inside `//`/`/* */` comments and
+// '...'/"..." string literals so a literal brace in a dependency notation
+// string doesn't desynchronize the count.
+func topLevelDepths(content []byte, lines []string) []int {
+	depths := make([]int, len(lines))
+	depth := 0
+	lineNo := 0
+	lineStart := 0
+
+	n := len(content)
+	for i := 0; i < n; {
+		for lineNo < len(lines) && i >= lineStart+len(lines[lineNo]) {
+			lineStart += len(lines[lineNo])
+			lineNo++
+			if lineNo < len(depths) {
+				depths[lineNo] = depth
+			}
+		}
+
+		c := content[i]
+		switch {
+		case c == '/' && i+1 < n && content[i+1] == '/':
+			for i < n && content[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < n && content[i+1] == '*':
+			i += 2
+			for i+1 < n && !(content[i] == '*' && content[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case c == '\'' || c == '"':
+			quote := c
+			i++
+			for i < n && content[i] != quote {
+				if content[i] == '\\' && i+1 < n {
+					i++
+				}
+				i++
+			}
+			i++
+		case c == '{':
+			depth++
+			i++
+		case c == '}':
+			depth--
+			i++
+		default:
+			i++
+		}
+	}
+
+	return depths
+}
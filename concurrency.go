@@ -0,0 +1,45 @@
+package main
+
+import "sync"
+
+// parallelDo runs fn(i) for each i in [0, n) using up to jobs concurrent
+// goroutines, waits for all of them to finish, and returns the first error
+// encountered (if any). Every i runs exactly once regardless of errors, so
+// fn must be safe to call even after a sibling call has failed; callers
+// that need to stop early on error should have fn check nothing shared and
+// just let the remaining goroutines finish their own independent work.
+func parallelDo(jobs, n int, fn func(i int) error) error {
+	if n == 0 {
+		return nil
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > n {
+		jobs = n
+	}
+
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diff implements a small internal/diff-style unified diff over line
+// slices, good enough to preview a rename's edits before anything is
+// written. It is not meant to be byte-identical to GNU diff, only to give
+// a reviewer (human or bot) a readable hunk-by-hunk picture of a change.
+
+const diffContext = 3
+
+type diffOp struct {
+	kind         byte // ' ', '-', or '+'
+	text         string
+	oldNo, newNo int // 1-based line numbers; 0 if not applicable to this side
+}
+
+// unifiedDiff returns a unified diff of oldContent -> newContent for path,
+// or "" if the two are identical.
+func unifiedDiff(path string, oldContent, newContent []byte) string {
+	oldLines := splitLines(string(oldContent))
+	newLines := splitLines(string(newContent))
+
+	ops := diffSequence(oldLines, newLines)
+	hunks := groupHunks(ops, diffContext)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n", path)
+	fmt.Fprintf(&b, "+++ b/%s\n", path)
+	for _, h := range hunks {
+		b.WriteString(hunkHeader(h))
+		for _, op := range h {
+			b.WriteByte(op.kind)
+			b.WriteString(op.text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffSequence computes a line-level diff via the standard LCS dynamic
+// program, then annotates each resulting op with its 1-based line number on
+// whichever side(s) it belongs to.
+func diffSequence(oldLines, newLines []string) []diffOp {
+	n, m := len(oldLines), len(newLines)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] >= dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{' ', oldLines[i], i + 1, j + 1})
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			ops = append(ops, diffOp{'-', oldLines[i], i + 1, 0})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', newLines[j], 0, j + 1})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', oldLines[i], i + 1, 0})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', newLines[j], 0, j + 1})
+	}
+	return ops
+}
+
+// groupHunks clusters changed ops (and `context` lines of surrounding
+// unchanged ops) into hunks, merging clusters that end up overlapping.
+func groupHunks(ops []diffOp, context int) [][]diffOp {
+	var changeRanges [][2]int
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		changeRanges = append(changeRanges, [2]int{i, j - 1})
+		i = j
+	}
+	if len(changeRanges) == 0 {
+		return nil
+	}
+
+	var merged [][2]int
+	for _, cr := range changeRanges {
+		lo, hi := cr[0]-context, cr[1]+context
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(ops)-1 {
+			hi = len(ops) - 1
+		}
+		if len(merged) > 0 && lo <= merged[len(merged)-1][1]+1 {
+			if hi > merged[len(merged)-1][1] {
+				merged[len(merged)-1][1] = hi
+			}
+		} else {
+			merged = append(merged, [2]int{lo, hi})
+		}
+	}
+
+	hunks := make([][]diffOp, len(merged))
+	for i, m := range merged {
+		hunks[i] = ops[m[0] : m[1]+1]
+	}
+	return hunks
+}
+
+func hunkHeader(ops []diffOp) string {
+	oldStart, oldCount, newStart, newCount := 0, 0, 0, 0
+	haveOld, haveNew := false, false
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+			if !haveOld {
+				oldStart, haveOld = op.oldNo, true
+			}
+		}
+		if op.kind != '-' {
+			newCount++
+			if !haveNew {
+				newStart, haveNew = op.newNo, true
+			}
+		}
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+}
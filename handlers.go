@@ -0,0 +1,187 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Handler computes the rename edit (if any) a single non-Java file needs,
+// given its path and current content. Java source itself is handled by the
+// renamer's own symbol-table-driven pass (renamer.go/typerename.go);
+// Handler covers everything else a real JVM project references the
+// package or class name from.
+type Handler interface {
+	// Match reports whether this handler claims the file at path.
+	Match(path string) bool
+	// ComputeEdit computes path's edit, or ok=false if the rename doesn't
+	// touch it.
+	ComputeEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error)
+}
+
+// handlerFunc adapts a match predicate and a compute function into a
+// Handler, so each file type below registers as a single literal instead
+// of a new named type.
+type handlerFunc struct {
+	match   func(path string) bool
+	compute func(path string, content []byte, oldName, newName string) (FileEdit, bool, error)
+}
+
+func (h handlerFunc) Match(path string) bool { return h.match(path) }
+
+func (h handlerFunc) ComputeEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	return h.compute(path, content, oldName, newName)
+}
+
+// byBase matches files whose exact basename is one of names.
+func byBase(names ...string) func(string) bool {
+	return func(path string) bool {
+		base := filepath.Base(path)
+		for _, n := range names {
+			if base == n {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// byExt matches files whose lowercased extension is one of exts.
+func byExt(exts ...string) func(string) bool {
+	return func(path string) bool {
+		ext := strings.ToLower(filepath.Ext(path))
+		for _, e := range exts {
+			if ext == e {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// byDir matches any file whose containing directory is, or ends in,
+// dirSuffix (given with forward slashes) - for files like
+// META-INF/services/* that are identified by location, not extension.
+func byDir(dirSuffix string) func(string) bool {
+	return func(path string) bool {
+		dir := filepath.ToSlash(filepath.Dir(path))
+		return dir == dirSuffix || strings.HasSuffix(dir, "/"+dirSuffix)
+	}
+}
+
+// handlers is every registered non-Java file handler, tried in order.
+// Adding support for another file type means adding one entry here - the
+// walk in collectAuxFileEdits never needs to change.
+var handlers = []Handler{
+	handlerFunc{byBase("pom.xml"), computePomEdit},
+	handlerFunc{byBase("build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"), computeGradleEdit},
+	handlerFunc{byBase("module-info.java"), computeModuleInfoEdit},
+	handlerFunc{byExt(".kt", ".kts"), computeKotlinEdit},
+	handlerFunc{byExt(".groovy"), computeGroovyEdit},
+	handlerFunc{byBase("application.properties"), computeSpringPropertiesEdit},
+	handlerFunc{byBase("application.yml", "application.yaml"), computeSpringYamlEdit},
+	handlerFunc{byDir("META-INF/services"), computeServiceLoaderEdit},
+	handlerFunc{byBase("AndroidManifest.xml"), computeAndroidManifestEdit},
+	handlerFunc{byExt(".pro"), computeProguardEdit},
+	handlerFunc{byBase("log4j.xml", "log4j2.xml", "log4j2-test.xml", "logback.xml", "logback-spring.xml", "logback-test.xml"), computeLoggingConfigEdit},
+}
+
+// renamedDottedName reports what s becomes if it is a whole dotted name
+// equal to oldName, or has oldName as a dotted prefix (oldName + "." +
+// rest) - the same exact-or-nested rule rewriteDecls and
+// rewriteTypeRenameFile apply to packages, imports, and qualified type
+// references.
+func renamedDottedName(s, oldName, newName string) (string, bool) {
+	if s == oldName {
+		return newName, true
+	}
+	if strings.HasPrefix(s, oldName+".") {
+		return newName + s[len(oldName):], true
+	}
+	return s, false
+}
+
+// isDottedNameChar reports whether b can appear inside a dotted name
+// (identifier characters plus '.'), used to bound matches in
+// findDottedOccurrences so a hit is never a substring of a longer,
+// unrelated identifier.
+func isDottedNameChar(b byte) bool {
+	return b == '_' || b == '$' || ('0' <= b && b <= '9') ||
+		('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// dottedNameKeyPrefixes are property/YAML keys whose value is itself a
+// dotted name, rather than part of one - e.g. "logging.level.com.example"
+// means "the logging level for package com.example", not a longer dotted
+// name "logging.level.com.example". A match immediately preceded by one of
+// these is a valid left boundary despite the preceding '.'.
+var dottedNameKeyPrefixes = []string{"logging.level."}
+
+// hasDottedNameKeyPrefix reports whether s up to start ends with a known
+// key prefix from dottedNameKeyPrefixes, itself starting at a valid left
+// boundary - so "accesslogging.level." doesn't count as ending in the
+// "logging.level." key just because it contains that substring.
+func hasDottedNameKeyPrefix(s string, start int) bool {
+	for _, p := range dottedNameKeyPrefixes {
+		if start < len(p) || s[start-len(p):start] != p {
+			continue
+		}
+		keyStart := start - len(p)
+		if keyStart == 0 || !isDottedNameChar(s[keyStart-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// findDottedOccurrences returns the byte ranges in s where name appears as
+// a whole dotted name - bounded on both sides by a non-identifier character
+// (or the string's edge) - for use on plain-text formats (properties, YAML,
+// ProGuard rules) that have no lexer of their own in this tool.
+//
+// A '.' immediately before the match is also rejected as a boundary, unlike
+// one immediately after: name is only a match on its own, never a suffix of
+// some longer dotted name (e.g. "com.foo" inside "org.com.foo" must not
+// match), whereas name followed by "." is the expected nested-prefix case
+// (e.g. "com.foo" inside "com.foo.Bar"). The one exception is a preceding
+// dottedNameKeyPrefixes entry (e.g. "logging.level."): there the '.' ends a
+// property key, not a longer dotted name, so the match is still valid (e.g.
+// "com.example" inside "logging.level.com.example").
+func findDottedOccurrences(s, name string) [][2]int {
+	var spans [][2]int
+	for i := 0; ; {
+		idx := strings.Index(s[i:], name)
+		if idx < 0 {
+			break
+		}
+		start := i + idx
+		end := start + len(name)
+		leftOK := start == 0 || (!isDottedNameChar(s[start-1]) && s[start-1] != '.') || hasDottedNameKeyPrefix(s, start)
+		rightOK := end == len(s) || !isDottedNameChar(s[end])
+		if leftOK && rightOK {
+			spans = append(spans, [2]int{start, end})
+			i = end
+		} else {
+			i = start + 1
+		}
+	}
+	return spans
+}
+
+// rewriteDottedOccurrences replaces every whole-token occurrence of oldName
+// in s - exact, or as a dotted prefix - with its renamed form.
+func rewriteDottedOccurrences(s, oldName, newName string) (string, bool) {
+	spans := findDottedOccurrences(s, oldName)
+	if len(spans) == 0 {
+		return s, false
+	}
+	var b strings.Builder
+	prev := 0
+	for _, sp := range spans {
+		b.WriteString(s[prev:sp[0]])
+		replaced, _ := renamedDottedName(s[sp[0]:sp[1]], oldName, newName)
+		b.WriteString(replaced)
+		prev = sp[1]
+	}
+	b.WriteString(s[prev:])
+	return b.String(), true
+}
@@ -0,0 +1,166 @@
+package main
+
+// javalex provides a minimal lexical scanner over Java source text. It is
+// not a full parser: it only needs to tell code apart from comments and
+// string/char literals so that identifier rewrites never touch text that
+// merely looks like an identifier inside a comment or a literal.
+
+type tokenKind int
+
+const (
+	tokOther tokenKind = iota // whitespace, punctuation, etc. - copied verbatim
+	tokIdent                  // a Java identifier or keyword
+	tokLineComment
+	tokBlockComment
+	tokString
+	tokChar
+)
+
+// javaToken is a slice of the original source annotated with its kind.
+type javaToken struct {
+	kind tokenKind
+	text string
+}
+
+// lexJava splits src into tokens. Only tokIdent tokens are candidates for
+// identifier-aware rewriting; everything else is reproduced byte-for-byte.
+func lexJava(src string) []javaToken {
+	var tokens []javaToken
+	n := len(src)
+	i := 0
+	for i < n {
+		c := src[i]
+
+		switch {
+		case c == '/' && i+1 < n && src[i+1] == '/':
+			j := i + 2
+			for j < n && src[j] != '\n' {
+				j++
+			}
+			tokens = append(tokens, javaToken{tokLineComment, src[i:j]})
+			i = j
+
+		case c == '/' && i+1 < n && src[i+1] == '*':
+			j := i + 2
+			for j+1 < n && !(src[j] == '*' && src[j+1] == '/') {
+				j++
+			}
+			if j+1 < n {
+				j += 2
+			} else {
+				j = n
+			}
+			tokens = append(tokens, javaToken{tokBlockComment, src[i:j]})
+			i = j
+
+		case c == '"':
+			j := scanQuoted(src, i, '"')
+			tokens = append(tokens, javaToken{tokString, src[i:j]})
+			i = j
+
+		case c == '\'':
+			j := scanQuoted(src, i, '\'')
+			tokens = append(tokens, javaToken{tokChar, src[i:j]})
+			i = j
+
+		case isJavaIdentStart(c):
+			j := i + 1
+			for j < n && isJavaIdentPart(src[j]) {
+				j++
+			}
+			tokens = append(tokens, javaToken{tokIdent, src[i:j]})
+			i = j
+
+		default:
+			tokens = append(tokens, javaToken{tokOther, src[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
+
+// scanQuoted returns the index just past the closing quote of a string or
+// char literal starting at src[start], handling backslash escapes. If the
+// literal is unterminated it returns len(src).
+func scanQuoted(src string, start int, quote byte) int {
+	n := len(src)
+	j := start + 1
+	for j < n {
+		if src[j] == '\\' && j+1 < n {
+			j += 2
+			continue
+		}
+		if src[j] == quote {
+			return j + 1
+		}
+		j++
+	}
+	return n
+}
+
+func isJavaIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isJavaIdentPart(c byte) bool {
+	return isJavaIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+var javaKeywords = map[string]bool{
+	"abstract": true, "assert": true, "boolean": true, "break": true, "byte": true,
+	"case": true, "catch": true, "char": true, "class": true, "const": true,
+	"continue": true, "default": true, "do": true, "double": true, "else": true,
+	"enum": true, "extends": true, "final": true, "finally": true, "float": true,
+	"for": true, "goto": true, "if": true, "implements": true, "import": true,
+	"instanceof": true, "int": true, "interface": true, "long": true, "native": true,
+	"new": true, "package": true, "private": true, "protected": true, "public": true,
+	"record": true, "return": true, "short": true, "static": true, "strictfp": true,
+	"super": true, "switch": true, "synchronized": true, "this": true, "throw": true,
+	"throws": true, "transient": true, "try": true, "void": true, "volatile": true,
+	"while": true, "var": true, "yield": true, "sealed": true, "permits": true,
+	"true": true, "false": true, "null": true,
+}
+
+// isJavaIdentifier reports whether s is a single legal Java identifier
+// (not a reserved keyword or literal).
+func isJavaIdentifier(s string) bool {
+	if s == "" || javaKeywords[s] {
+		return false
+	}
+	if !isJavaIdentStart(s[0]) || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isJavaIdentPart(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isJavaPackageName reports whether s is a legal dotted Java package/type
+// path, e.g. "com.foo.Bar".
+func isJavaPackageName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, part := range splitDotted(s) {
+		if !isJavaIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitDotted(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// computeModuleInfoEdit rewrites every dotted name in a module-info.java
+// that equals oldName or is nested under it - the module name itself plus
+// any exports/opens/requires/uses/provides...with clause - using the same
+// Java lexer the renamer uses for ordinary source, since module-info.java
+// is lexically plain Java.
+func computeModuleInfoEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	tokens := lexJava(string(content))
+	offsets := tokenOffsets(tokens)
+	chains := collectDottedChains(string(content), tokens, offsets)
+
+	var spans []struct {
+		from, to int
+		text     string
+	}
+	for _, c := range chains {
+		if replaced, changed := renamedDottedName(c.text, oldName, newName); changed {
+			spans = append(spans, struct {
+				from, to int
+				text     string
+			}{c.from, c.to, replaced})
+		}
+	}
+	if len(spans) == 0 {
+		return FileEdit{}, false, nil
+	}
+	return FileEdit{Path: path, NewContent: applySpans(content, spans)}, true, nil
+}
+
+// packageAndImportChains returns the dotted chain immediately following
+// each `package` or `import` keyword in a Java-like source file (Kotlin and
+// Groovy share Java's package/import syntax), so a package rename can touch
+// those declarations without rewriting arbitrary body references the way a
+// full type rename would.
+func packageAndImportChains(content string, tokens []javaToken, offsets []int) []dottedChain {
+	chains := collectDottedChains(content, tokens, offsets)
+	chainAt := make(map[int]dottedChain, len(chains))
+	for _, c := range chains {
+		chainAt[c.from] = c
+	}
+
+	var out []dottedChain
+	for i, t := range tokens {
+		if t.kind != tokIdent || (t.text != "package" && t.text != "import") {
+			continue
+		}
+		for j := i + 1; j < len(tokens); j++ {
+			if tokens[j].kind == tokOther && strings.TrimSpace(tokens[j].text) == "" {
+				continue
+			}
+			if c, ok := chainAt[offsets[j]]; ok {
+				out = append(out, c)
+			}
+			break
+		}
+	}
+	return out
+}
+
+// computeSourceDeclEdit rewrites the package declaration and imports of a
+// Java-like source file, reusing the Java lexer. It backs both
+// computeKotlinEdit and computeGroovyEdit.
+func computeSourceDeclEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	tokens := lexJava(string(content))
+	offsets := tokenOffsets(tokens)
+	chains := packageAndImportChains(string(content), tokens, offsets)
+
+	var spans []struct {
+		from, to int
+		text     string
+	}
+	for _, c := range chains {
+		if replaced, changed := renamedDottedName(c.text, oldName, newName); changed {
+			spans = append(spans, struct {
+				from, to int
+				text     string
+			}{c.from, c.to, replaced})
+		}
+	}
+	if len(spans) == 0 {
+		return FileEdit{}, false, nil
+	}
+	return FileEdit{Path: path, NewContent: applySpans(content, spans)}, true, nil
+}
+
+func computeKotlinEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	return computeSourceDeclEdit(path, content, oldName, newName)
+}
+
+func computeGroovyEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	return computeSourceDeclEdit(path, content, oldName, newName)
+}
+
+// rewriteLinesSkippingComments rewrites every whole-token occurrence of
+// oldName on each line of content, skipping lines whose first
+// non-whitespace character starts a comment. This is good enough for the
+// flat key=value/key: value/one-FQCN-per-line files Spring configuration,
+// service-loader registrations, and ProGuard rules are written in, without
+// a full properties/YAML parser.
+func rewriteLinesSkippingComments(path string, content []byte, oldName, newName string, commentPrefixes ...string) (FileEdit, bool, error) {
+	lines := splitLinesKeepEOL(content)
+	var out bytes.Buffer
+	changed := false
+
+	for _, line := range lines {
+		text, eol := splitEOL(line)
+		trimmed := strings.TrimSpace(text)
+		isComment := false
+		for _, p := range commentPrefixes {
+			if strings.HasPrefix(trimmed, p) {
+				isComment = true
+				break
+			}
+		}
+		if !isComment {
+			if rewritten, lineChanged := rewriteDottedOccurrences(text, oldName, newName); lineChanged {
+				text = rewritten
+				changed = true
+			}
+		}
+		out.WriteString(text)
+		out.WriteString(eol)
+	}
+
+	if !changed {
+		return FileEdit{}, false, nil
+	}
+	return FileEdit{Path: path, NewContent: out.Bytes()}, true, nil
+}
+
+func computeSpringPropertiesEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	return rewriteLinesSkippingComments(path, content, oldName, newName, "#", "!")
+}
+
+func computeSpringYamlEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	return rewriteLinesSkippingComments(path, content, oldName, newName, "#")
+}
+
+// computeServiceLoaderEdit handles a META-INF/services/<FQCN> file: its
+// basename is the fully-qualified service interface name, and each
+// non-comment line names an implementing class, so both the filename and
+// the content can need rewriting.
+func computeServiceLoaderEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	edit, contentChanged, err := rewriteLinesSkippingComments(path, content, oldName, newName, "#")
+	if err != nil {
+		return FileEdit{}, false, err
+	}
+	if !contentChanged {
+		edit = FileEdit{Path: path}
+	}
+
+	if renamed, ok := renamedDottedName(filepath.Base(path), oldName, newName); ok {
+		edit.RenameTo = filepath.Join(filepath.Dir(path), renamed)
+	}
+
+	if !contentChanged && edit.RenameTo == "" {
+		return FileEdit{}, false, nil
+	}
+	return edit, true, nil
+}
+
+func computeProguardEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	return rewriteLinesSkippingComments(path, content, oldName, newName, "#")
+}
+
+// xmlAttrSpans walks content as XML and, for each start element, calls
+// visit with its local name and the exact byte span of that start tag
+// (attributes included) within content. encoding/xml only reports
+// element/char-data offsets via InputOffset, not a tag's own start, so this
+// reconstructs it from the previous token's end offset.
+func xmlAttrSpans(content []byte, visit func(name string, tagFrom, tagTo int)) error {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	var prevOffset int64
+	for {
+		start := prevOffset
+		tok, err := dec.Token()
+		end := dec.InputOffset()
+		prevOffset = end
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			visit(se.Name.Local, int(start), int(end))
+		}
+	}
+}
+
+var (
+	attrNameRe        = regexp.MustCompile(`(?:^|\s)name\s*=\s*"([^"]*)"`)
+	attrClassRe       = regexp.MustCompile(`(?:^|\s)class\s*=\s*"([^"]*)"`)
+	attrValueRe       = regexp.MustCompile(`(?:^|\s)value\s*=\s*"([^"]*)"`)
+	attrPackageRe     = regexp.MustCompile(`(?:^|\s)package\s*=\s*"([^"]*)"`)
+	attrAndroidNameRe = regexp.MustCompile(`android:name\s*=\s*"([^"]*)"`)
+)
+
+// findAttr returns the byte span (relative to raw) and value of re's first
+// submatch in raw, the exact text an attribute's value occupies, so a
+// caller can compute its absolute offset within the whole document.
+func findAttr(raw string, re *regexp.Regexp) (from, to int, val string, ok bool) {
+	loc := re.FindStringSubmatchIndex(raw)
+	if loc == nil {
+		return 0, 0, "", false
+	}
+	return loc[2], loc[3], raw[loc[2]:loc[3]], true
+}
+
+// computeLoggingConfigEdit rewrites logger/category names and appender or
+// param class/value attributes in a log4j or logback XML config that
+// reference the renamed package.
+func computeLoggingConfigEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	var spans []struct {
+		from, to int
+		text     string
+	}
+
+	err := xmlAttrSpans(content, func(name string, tagFrom, tagTo int) {
+		if name != "logger" && name != "category" && name != "appender" && name != "appender-ref" && name != "param" {
+			return
+		}
+		raw := string(content[tagFrom:tagTo])
+		for _, re := range []*regexp.Regexp{attrNameRe, attrClassRe, attrValueRe} {
+			if from, to, val, ok := findAttr(raw, re); ok {
+				if replaced, changed := renamedDottedName(val, oldName, newName); changed {
+					spans = append(spans, struct {
+						from, to int
+						text     string
+					}{tagFrom + from, tagFrom + to, replaced})
+				}
+			}
+		}
+	})
+	if err != nil {
+		return FileEdit{}, false, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(spans) == 0 {
+		return FileEdit{}, false, nil
+	}
+	return FileEdit{Path: path, NewContent: applySpans(content, spans)}, true, nil
+}
+
+// computeAndroidManifestEdit rewrites the root <manifest package="..."> and
+// any android:name="..." attribute that holds a fully-qualified class name
+// (names starting with "." are package-relative and left untouched, since
+// they carry no package prefix to rewrite).
+func computeAndroidManifestEdit(path string, content []byte, oldName, newName string) (FileEdit, bool, error) {
+	var spans []struct {
+		from, to int
+		text     string
+	}
+
+	err := xmlAttrSpans(content, func(name string, tagFrom, tagTo int) {
+		raw := string(content[tagFrom:tagTo])
+		if name == "manifest" {
+			if from, to, val, ok := findAttr(raw, attrPackageRe); ok && val == oldName {
+				spans = append(spans, struct {
+					from, to int
+					text     string
+				}{tagFrom + from, tagFrom + to, newName})
+			}
+		}
+		if loc := attrAndroidNameRe.FindStringSubmatchIndex(raw); loc != nil {
+			from, to := loc[2], loc[3]
+			val := raw[from:to]
+			if strings.HasPrefix(val, ".") {
+				return
+			}
+			if replaced, changed := renamedDottedName(val, oldName, newName); changed {
+				spans = append(spans, struct {
+					from, to int
+					text     string
+				}{tagFrom + from, tagFrom + to, replaced})
+			}
+		}
+	})
+	if err != nil {
+		return FileEdit{}, false, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(spans) == 0 {
+		return FileEdit{}, false, nil
+	}
+	return FileEdit{Path: path, NewContent: applySpans(content, spans)}, true, nil
+}
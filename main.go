@@ -6,13 +6,20 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
 func main() {
 	projectDir := flag.String("dir", "", "Path to the Java project directory")
-	oldName := flag.String("old", "", "Old project name (e.g., com.example.oldproject)")
-	newName := flag.String("new", "", "New project name (e.g., com.newcompany.newproject)")
+	oldName := flag.String("old", "", "Old package or fully-qualified class name (e.g., com.example.oldproject or com.example.old.OldClass)")
+	newName := flag.String("new", "", "New package or fully-qualified class name (e.g., com.newcompany.newproject or com.newcompany.new.NewClass)")
+	kindFlag := flag.String("kind", string(kindAuto), "What --old/--new refer to: auto, package, or type")
+	dryRun := flag.Bool("dry-run", false, "Print the planned edits and file moves without writing anything")
+	format := flag.String("format", "diff", "Output format for --dry-run: diff or json")
+	backupDir := flag.String("backup-dir", "", "Directory to back up originals to before applying (default: an auto-created, auto-removed temp directory under --dir)")
+	noBackup := flag.Bool("no-backup", false, "Skip taking a backup; a failure partway through cannot be rolled back")
+	jobsFlag := flag.Int("jobs", 0, "Number of files to read/edit concurrently (default: GOMAXPROCS)")
 	flag.Parse()
 
 	if *projectDir == "" || *oldName == "" || *newName == "" {
@@ -20,163 +27,129 @@ func main() {
 		log.Fatal("All flags --dir, --old, and --new are required.")
 	}
 
-	fmt.Printf("Renaming Java project in %s from '%s' to '%s'\n", *projectDir, *oldName, *newName)
-
-	err := filepath.Walk(*projectDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip .git and target directories
-		if info.IsDir() && (info.Name() == ".git" || info.Name() == "target") {
-			return filepath.SkipDir
-		}
-
-		// Process .java files
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".java") {
-			return processJavaFile(path, *oldName, *newName)
-		}
+	kind := renameKind(*kindFlag)
+	if kind != kindAuto && kind != kindPackage && kind != kindType {
+		log.Fatalf("Invalid --kind %q: must be auto, package, or type", *kindFlag)
+	}
+	if *format != "diff" && *format != "json" {
+		log.Fatalf("Invalid --format %q: must be diff or json", *format)
+	}
 
-		// Process build files (e.g., pom.xml, build.gradle)
-		if !info.IsDir() && (info.Name() == "pom.xml" || info.Name() == "build.gradle") {
-			return processBuildFile(path, *oldName, *newName)
-		}
+	jobs := *jobsFlag
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
 
-		return nil
-	})
+	// Progress/info output goes to stderr, not stdout: --dry-run --format
+	// json must produce a single JSON document on stdout suitable for
+	// piping into other tooling.
+	fmt.Fprintf(os.Stderr, "Renaming Java project in %s from '%s' to '%s'\n", *projectDir, *oldName, *newName)
 
+	ren, err := newRenamer(*projectDir, *oldName, *newName, kind, jobs)
 	if err != nil {
-		log.Fatalf("Error walking the directory: %v", err)
+		log.Fatalf("Error scanning project: %v", err)
 	}
+	fmt.Fprintf(os.Stderr, "Resolved --old as a %s\n", ren.kind)
 
-	fmt.Println("Renaming complete. Please verify the changes and rebuild your Java project.")
-}
-
-func processJavaFile(filePath, oldName, newName string) error {
-	fmt.Printf("Processing Java file: %s\n", filePath)
+	if err := ren.PrepareRename(); err != nil {
+		log.Fatalf("Rename is not safe to apply: %v", err)
+	}
 
-	content, err := os.ReadFile(filePath)
+	edits, err := ren.Plan()
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filePath, err)
+		log.Fatalf("Error planning rename: %v", err)
 	}
 
-	originalContent := string(content)
-	modifiedContent := originalContent
+	auxEdits, err := collectAuxFileEdits(*projectDir, *oldName, *newName, jobs)
+	if err != nil {
+		log.Fatalf("Error planning auxiliary file edits: %v", err)
+	}
+	edits = append(edits, auxEdits...)
 
-	// Replace package declarations
-	modifiedContent = strings.ReplaceAll(modifiedContent, "package "+oldName, "package "+newName)
-	modifiedContent = strings.ReplaceAll(modifiedContent, "import "+oldName, "import "+newName)
+	if *dryRun {
+		if err := printPlan(edits, *format); err != nil {
+			log.Fatalf("Error printing plan: %v", err)
+		}
+		return
+	}
 
-	// Determine old and new base package paths for file renaming
-	oldPackagePath := strings.ReplaceAll(oldName, ".", string(filepath.Separator))
-	newPackagePath := strings.ReplaceAll(newName, ".", string(filepath.Separator))
+	opts := ApplyOptions{BackupDir: *backupDir, NoBackup: *noBackup}
+	if err := ren.Apply(edits, opts); err != nil {
+		log.Fatalf("Error applying rename: %v", err)
+	}
 
-	// Attempt to derive old and new class names based on common patterns
-	// This is a simplified approach and might need refinement for complex cases
+	fmt.Println("Renaming complete. Please verify the changes and rebuild your Java project.")
+}
 
-	// If the oldName is a package, and the class name is part of it, try to infer new class name
-	if strings.HasPrefix(oldName, newName) { // e.g., old: com.foo.bar.MyClass, new: com.foo.bar
-		// This case is tricky, might need more sophisticated parsing
-	} else {
-		// Simple case: oldName is a full package + class name, newName is a full package + class name
-		// This part needs to be more robust. For now, focus on package replacement.
+// collectAuxFileEdits walks the project for every non-Java file a registered
+// Handler claims (build files, Kotlin/Groovy sources, Spring config,
+// service-loader registrations, manifests, ProGuard rules, logging config,
+// ...) and computes the edit each one needs, without writing anything.
+// module-info.java is the one ".java" file handled here rather than by the
+// renamer's symbol-table pass, since it has no package/type declaration of
+// its own to drive that pass.
+//
+// Discovery (the walk, matching each file to its Handler) is sequential;
+// the read-and-compute work for each match - the expensive part on a large
+// tree - runs across up to jobs goroutines.
+func collectAuxFileEdits(root, oldName, newName string, jobs int) ([]FileEdit, error) {
+	type match struct {
+		path    string
+		handler Handler
 	}
 
-	// Replace class name occurrences (this is very basic and might replace too much)
-	// A more robust solution would involve parsing the Java code.
-	// For now, let's focus on package and file path renaming.
-	// modifiedContent = strings.ReplaceAll(modifiedContent, oldClassName, newClassName)
-
-	if modifiedContent != originalContent {
-		err = os.WriteFile(filePath, []byte(modifiedContent), 0644)
+	var matches []match
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			return fmt.Errorf("failed to write file %s: %w", filePath, err)
+			return err
 		}
-		fmt.Printf("Updated content of %s\n", filePath)
-	}
-
-	// Rename file path if package path changes
-	if strings.Contains(filePath, oldPackagePath) {
-		newFilePath := strings.Replace(filePath, oldPackagePath, newPackagePath, 1)
-		if newFilePath != filePath {
-			// Ensure the new directory exists
-			newDir := filepath.Dir(newFilePath)
-			if _, err := os.Stat(newDir); os.IsNotExist(err) {
-				err = os.MkdirAll(newDir, 0755)
-				if err != nil {
-					return fmt.Errorf("failed to create directory %s: %w", newDir, err)
-				}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "target" || strings.HasPrefix(d.Name(), stagingDirPrefix) || strings.HasPrefix(d.Name(), backupDirPrefix) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".java") && d.Name() != "module-info.java" {
+			return nil
+		}
 
-			err = os.Rename(filePath, newFilePath)
-			if err != nil {
-				return fmt.Errorf("failed to rename file from %s to %s: %w", filePath, newFilePath, err)
+		for _, h := range handlers {
+			if h.Match(path) {
+				matches = append(matches, match{path: path, handler: h})
+				break
 			}
-			fmt.Printf("Renamed file from %s to %s\n", filePath, newFilePath)
 		}
-	}
-
-	return nil
-}
-
-func processBuildFile(filePath, oldName, newName string) error {
-	fmt.Printf("Processing build file: %s\n", filePath)
-
-	content, err := os.ReadFile(filePath)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filePath, err)
+		return nil, err
 	}
 
-	originalContent := string(content)
-	modifiedContent := originalContent
-
-	// Simple string replacement for build files. This might need more sophisticated XML/Gradle parsing.
-	// For Maven pom.xml: groupId, artifactId
-	// For Gradle build.gradle: group, artifactId
-	modifiedContent = strings.ReplaceAll(modifiedContent, oldName, newName)
-
-	// Attempt to replace common Maven/Gradle artifact/group IDs if oldName is a package
-	// This is a heuristic and might not cover all cases.
-	oldParts := strings.Split(oldName, ".")
-	newParts := strings.Split(newName, ".")
-
-	if len(oldParts) > 0 && len(newParts) > 0 {
-		oldArtifact := oldParts[len(oldParts)-1]
-		newArtifact := newParts[len(newParts)-1]
-		modifiedContent = strings.ReplaceAll(modifiedContent, oldArtifact, newArtifact)
-
-		oldGroup := strings.Join(oldParts[:len(oldParts)-1], ".")
-		newGroup := strings.Join(newParts[:len(newParts)-1], ".")
-		if oldGroup != "" && newGroup != "" {
-			modifiedContent = strings.ReplaceAll(modifiedContent, oldGroup, newGroup)
+	results := make([]*FileEdit, len(matches))
+	err = parallelDo(jobs, len(matches), func(i int) error {
+		m := matches[i]
+		content, rerr := os.ReadFile(m.path)
+		if rerr != nil {
+			return fmt.Errorf("reading %s: %w", m.path, rerr)
 		}
-	}
-
-
-	if modifiedContent != originalContent {
-		err = os.WriteFile(filePath, []byte(modifiedContent), 0644)
-		if err != nil {
-			return fmt.Errorf("failed to write file %s: %w", filePath, err)
+		edit, changed, cerr := m.handler.ComputeEdit(m.path, content, oldName, newName)
+		if cerr != nil {
+			return cerr
 		}
-		fmt.Printf("Updated content of %s\n", filePath)
-	}
-
-	return nil
-}
-
-// Helper function to rename directories
-func renameDirectory(oldPath, newPath string) error {
-	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
-		return nil // Directory doesn't exist, nothing to rename
-	}
-	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
-		return fmt.Errorf("new directory %s already exists", newPath)
+		if changed {
+			results[i] = &edit
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	err := os.Rename(oldPath, newPath)
-	if err != nil {
-		return fmt.Errorf("failed to rename directory from %s to %s: %w", oldPath, newPath, err)
+	var edits []FileEdit
+	for _, e := range results {
+		if e != nil {
+			edits = append(edits, *e)
+		}
 	}
-	fmt.Printf("Renamed directory from %s to %s\n", oldPath, newPath)
-	return nil
+	return edits, nil
 }
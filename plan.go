@@ -0,0 +1,393 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// stagingDirPrefix marks the temp directory the renamer stages new file
+// content into before swapping it into place. The walker skips any
+// directory with this prefix so a leftover staging dir from a previous,
+// interrupted run is never mistaken for project source.
+const stagingDirPrefix = ".rename-staging"
+
+// backupDirPrefix marks the auto-created backup directory used for
+// rollback when the caller doesn't pass an explicit --backup-dir. Like
+// stagingDirPrefix, the walker skips it.
+const backupDirPrefix = ".rename-backup"
+
+// FileEdit describes a single planned change to one file: new content to
+// write, a new path to move it to, or both. A zero-value NewContent means
+// the file's bytes are unchanged and only RenameTo applies.
+type FileEdit struct {
+	Path       string
+	NewContent []byte
+	RenameTo   string
+}
+
+// Plan walks the symbol table built by newRenamer and computes every edit
+// and file move the rename requires, without touching disk. Callers must
+// call PrepareRename first; Plan does not re-validate.
+func (r *renamer) Plan() ([]FileEdit, error) {
+	if r.kind == kindType {
+		return r.planTypeRename()
+	}
+	return r.planPackageRename()
+}
+
+// planPackageRename handles a plain package rename: rewrite the package
+// declaration and imports in every affected file, and move declaring files
+// into the new package directory.
+func (r *renamer) planPackageRename() ([]FileEdit, error) {
+	oldPath := strings.ReplaceAll(r.oldName, ".", string(filepath.Separator))
+	newPath := strings.ReplaceAll(r.newName, ".", string(filepath.Separator))
+
+	results := make([]*FileEdit, len(r.table.files))
+	err := parallelDo(r.jobs, len(r.table.files), func(i int) error {
+		jf := r.table.files[i]
+		content, err := os.ReadFile(jf.path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", jf.path, err)
+		}
+
+		newContent, changed := rewriteDecls(content, jf, r.oldName, r.newName)
+
+		var renameTo string
+		if (jf.pkg == r.oldName || strings.HasPrefix(jf.pkg, r.oldName+".")) && strings.Contains(jf.path, oldPath) {
+			renameTo = strings.Replace(jf.path, oldPath, newPath, 1)
+		}
+
+		if changed || renameTo != "" {
+			results[i] = &FileEdit{Path: jf.path, NewContent: newContent, RenameTo: renameTo}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []FileEdit
+	for _, e := range results {
+		if e != nil {
+			edits = append(edits, *e)
+		}
+	}
+	return edits, nil
+}
+
+// rewriteDecls rewrites the package declaration and any import whose
+// dotted path equals oldName or is nested under it (oldName + "."), using
+// the byte ranges recorded by parseJavaFile so only the dotted name itself
+// is touched - never a comment, string literal, or unrelated identifier
+// that happens to contain the same substring.
+func rewriteDecls(content []byte, jf *javaFile, oldName, newName string) ([]byte, bool) {
+	type span struct {
+		from, to int
+		replace  string
+	}
+	var spans []span
+
+	if jf.pkg == oldName {
+		spans = append(spans, span{jf.pkgFrom, jf.pkgTo, newName})
+	} else if strings.HasPrefix(jf.pkg, oldName+".") {
+		spans = append(spans, span{jf.pkgFrom, jf.pkgTo, newName + jf.pkg[len(oldName):]})
+	}
+
+	for _, imp := range jf.imports {
+		if imp.path == oldName {
+			spans = append(spans, span{imp.start, imp.end, newName})
+		} else if strings.HasPrefix(imp.path, oldName+".") {
+			spans = append(spans, span{imp.start, imp.end, newName + imp.path[len(oldName):]})
+		}
+	}
+
+	if len(spans) == 0 {
+		return content, false
+	}
+
+	// Apply from the end of the file backward so earlier offsets stay valid.
+	out := append([]byte(nil), content...)
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		tail := append([]byte(nil), out[s.to:]...)
+		out = append(out[:s.from], append([]byte(s.replace), tail...)...)
+	}
+	return out, true
+}
+
+// ApplyOptions controls the safety net Apply builds around the swap.
+type ApplyOptions struct {
+	// BackupDir, if set, is where originals are copied before the swap so
+	// a failed run can be rolled back by hand. Left empty, Apply picks a
+	// temp directory under root and removes it itself once the swap
+	// succeeds; an explicit BackupDir is left in place either way, since
+	// the caller asked for it.
+	BackupDir string
+	// NoBackup skips taking any backup at all. A failure partway through
+	// the swap then can't be rolled back - only use this when the caller
+	// has their own safety net (e.g. a clean git worktree).
+	NoBackup bool
+}
+
+// backupManifestEntry records where one original file's pre-swap bytes
+// were copied to, so rollback knows what to restore and where.
+type backupManifestEntry struct {
+	OriginalPath string
+	BackupPath   string
+	RenameTo     string
+}
+
+// Apply stages every edit's final content into a temp directory under root,
+// then applies it in two phases: content updates swapped into place at
+// each file's original path, concurrently (safe, since this phase never
+// moves a file - every goroutine touches a distinct original path); then
+// any file moves applied one at a time, deepest paths first, so a move
+// never needs a directory another still-pending move is about to vacate or
+// displace. Before either phase begins, every file about to be touched is
+// backed up; if anything fails partway through, Apply restores the tree
+// from that backup rather than leaving a half-renamed project.
+func (r *renamer) Apply(edits []FileEdit, opts ApplyOptions) error {
+	stagingRoot, err := os.MkdirTemp(r.root, stagingDirPrefix+"-")
+	if err != nil {
+		return fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingRoot)
+
+	type staged struct {
+		stagedPath string
+		edit       FileEdit
+	}
+	prepared := make([]staged, len(edits))
+
+	err = parallelDo(r.jobs, len(edits), func(i int) error {
+		e := edits[i]
+		content := e.NewContent
+		if content == nil {
+			var rerr error
+			content, rerr = os.ReadFile(e.Path)
+			if rerr != nil {
+				return fmt.Errorf("reading %s: %w", e.Path, rerr)
+			}
+		}
+
+		stagedPath := filepath.Join(stagingRoot, fmt.Sprintf("%d-%s", i, filepath.Base(e.Path)))
+		if err := writeStagedFile(stagedPath, content); err != nil {
+			return fmt.Errorf("staging %s: %w", e.Path, err)
+		}
+		prepared[i] = staged{stagedPath: stagedPath, edit: e}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var backupDir string
+	manifest := make([]backupManifestEntry, len(prepared))
+	removeBackupOnSuccess := false
+
+	if !opts.NoBackup {
+		backupDir = opts.BackupDir
+		if backupDir == "" {
+			backupDir, err = os.MkdirTemp(r.root, backupDirPrefix+"-")
+			if err != nil {
+				return fmt.Errorf("creating backup directory: %w", err)
+			}
+			removeBackupOnSuccess = true
+		} else if err := os.MkdirAll(backupDir, 0755); err != nil {
+			return fmt.Errorf("creating backup directory %s: %w", backupDir, err)
+		}
+
+		err = parallelDo(r.jobs, len(prepared), func(i int) error {
+			p := prepared[i]
+			original, rerr := os.ReadFile(p.edit.Path)
+			if rerr != nil {
+				return fmt.Errorf("backing up %s: %w", p.edit.Path, rerr)
+			}
+			backupPath := filepath.Join(backupDir, fmt.Sprintf("%d-%s", i, filepath.Base(p.edit.Path)))
+			if err := writeStagedFile(backupPath, original); err != nil {
+				return fmt.Errorf("backing up %s: %w", p.edit.Path, err)
+			}
+			manifest[i] = backupManifestEntry{OriginalPath: p.edit.Path, BackupPath: backupPath, RenameTo: p.edit.RenameTo}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Phase: swap each edit's new content into place at its original path,
+	// concurrently.
+	var mu sync.Mutex
+	var appliedInPlace []backupManifestEntry
+	swapErr := parallelDo(r.jobs, len(prepared), func(i int) error {
+		p := prepared[i]
+		if err := renameOrCopy(p.stagedPath, p.edit.Path); err != nil {
+			return fmt.Errorf("applying edit to %s: %w", p.edit.Path, err)
+		}
+		if !opts.NoBackup {
+			mu.Lock()
+			appliedInPlace = append(appliedInPlace, backupManifestEntry{OriginalPath: manifest[i].OriginalPath, BackupPath: manifest[i].BackupPath})
+			mu.Unlock()
+		}
+		return nil
+	})
+	if swapErr != nil {
+		return r.failAndRollback(appliedInPlace, opts, swapErr, backupDir)
+	}
+
+	// Phase: apply file moves one at a time, deepest paths first.
+	var moves []int
+	for i, p := range prepared {
+		if p.edit.RenameTo != "" && p.edit.RenameTo != p.edit.Path {
+			moves = append(moves, i)
+		}
+	}
+	sort.Slice(moves, func(a, b int) bool {
+		return pathDepth(prepared[moves[a]].edit.Path) > pathDepth(prepared[moves[b]].edit.Path)
+	})
+
+	for _, i := range moves {
+		p := prepared[i]
+		if err := os.MkdirAll(filepath.Dir(p.edit.RenameTo), 0755); err != nil {
+			return r.failAndRollback(manifest, opts, fmt.Errorf("creating directory for %s: %w", p.edit.RenameTo, err), backupDir)
+		}
+		if err := renameOrCopy(p.edit.Path, p.edit.RenameTo); err != nil {
+			return r.failAndRollback(manifest, opts, fmt.Errorf("moving %s to %s: %w", p.edit.Path, p.edit.RenameTo, err), backupDir)
+		}
+	}
+	for _, i := range moves {
+		removeEmptyDirChain(filepath.Dir(prepared[i].edit.Path), r.root)
+	}
+
+	if removeBackupOnSuccess {
+		os.RemoveAll(backupDir)
+	}
+	return nil
+}
+
+// removeEmptyDirChain removes dir and any now-empty ancestor directories
+// below stopAt, so a package rename that moves every file out of a
+// directory doesn't leave it (or its now-empty parents) behind. Best
+// effort: any error, or a directory that still has entries, just stops the
+// walk rather than failing the rename.
+func removeEmptyDirChain(dir, stopAt string) {
+	stopAt = filepath.Clean(stopAt)
+	for {
+		dir = filepath.Clean(dir)
+		if dir == stopAt || dir == "." || dir == string(filepath.Separator) {
+			return
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// pathDepth counts path's separators, so sorting by it descending visits
+// the deepest, most-nested paths first.
+func pathDepth(path string) int {
+	return strings.Count(filepath.ToSlash(path), "/")
+}
+
+// failAndRollback restores every file named in applied (the prefix of the
+// manifest that was actually swapped before the error occurred) from its
+// backup, then returns origErr wrapped with whatever the rollback did.
+func (r *renamer) failAndRollback(applied []backupManifestEntry, opts ApplyOptions, origErr error, backupDir string) error {
+	if opts.NoBackup {
+		return fmt.Errorf("%w (no backup was taken with --no-backup; tree may be partially renamed)", origErr)
+	}
+	if rerr := restoreFromManifest(applied); rerr != nil {
+		return fmt.Errorf("%w (rollback also failed: %v; originals are still in %s)", origErr, rerr, backupDir)
+	}
+	return fmt.Errorf("%w (rolled back %d already-applied change(s) using backup in %s)", origErr, len(applied), backupDir)
+}
+
+// restoreFromManifest restores each entry's original bytes to its original
+// path and removes the rename target if the file had been moved, undoing
+// exactly the edits the manifest covers. It restores every entry it can
+// even if one fails - a blocked rename target shouldn't stop the rest of
+// the tree from being put back - and returns a combined error naming every
+// entry that couldn't be restored.
+func restoreFromManifest(manifest []backupManifestEntry) error {
+	var failed []string
+	for _, entry := range manifest {
+		content, err := os.ReadFile(entry.BackupPath)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("reading backup for %s: %v", entry.OriginalPath, err))
+			continue
+		}
+		if err := writeStagedFile(entry.OriginalPath, content); err != nil {
+			failed = append(failed, fmt.Sprintf("restoring %s: %v", entry.OriginalPath, err))
+			continue
+		}
+		if entry.RenameTo != "" && entry.RenameTo != entry.OriginalPath {
+			if err := os.Remove(entry.RenameTo); err != nil && !isRemoveNoOp(err) {
+				failed = append(failed, fmt.Sprintf("removing moved file %s during rollback: %v", entry.RenameTo, err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d file(s) could not be fully restored: %s", len(failed), len(manifest), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// isRemoveNoOp reports whether an os.Remove failure means there was never
+// anything to remove, rather than a genuine rollback failure: either the
+// target doesn't exist, or - when the move never got far enough to create
+// it because its parent path was blocked by a plain file - its parent isn't
+// even a directory, which os.Remove also surfaces as an error rather than
+// treating as "not found".
+func isRemoveNoOp(err error) bool {
+	return os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR)
+}
+
+func writeStagedFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// renameOrCopy moves src to dst via os.Rename, falling back to a copy when
+// the two paths are on different filesystems (EXDEV).
+func renameOrCopy(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(err.Error(), "cross-device") {
+		return err
+	}
+
+	in, ferr := os.Open(src)
+	if ferr != nil {
+		return ferr
+	}
+	defer in.Close()
+
+	out, ferr := os.Create(dst)
+	if ferr != nil {
+		return ferr
+	}
+	if _, ferr = io.Copy(out, in); ferr != nil {
+		out.Close()
+		return ferr
+	}
+	if ferr = out.Close(); ferr != nil {
+		return ferr
+	}
+	return os.Remove(src)
+}
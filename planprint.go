@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// printPlan renders a planned []FileEdit for review before anything is
+// written, mirroring how an LSP client previews a WorkspaceEdit.
+func printPlan(edits []FileEdit, format string) error {
+	switch format {
+	case "json":
+		return printPlanJSON(edits)
+	default:
+		return printPlanDiff(edits)
+	}
+}
+
+func printPlanDiff(edits []FileEdit) error {
+	for _, e := range edits {
+		if e.NewContent != nil {
+			old, err := os.ReadFile(e.Path)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", e.Path, err)
+			}
+			if d := unifiedDiff(e.Path, old, e.NewContent); d != "" {
+				fmt.Print(d)
+			}
+		}
+		if e.RenameTo != "" {
+			fmt.Printf("rename %s => %s\n", e.Path, e.RenameTo)
+		}
+	}
+	return nil
+}
+
+// jsonPlan is the `--format=json` document: every content edit as a
+// unified diff, plus every file move, so the plan can be piped into other
+// tooling (a code-review bot, a second pass of this same tool, etc.).
+type jsonPlan struct {
+	Edits   []jsonEdit   `json:"edits"`
+	Renames []jsonRename `json:"renames"`
+}
+
+type jsonEdit struct {
+	Path string `json:"path"`
+	Diff string `json:"diff"`
+}
+
+type jsonRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func printPlanJSON(edits []FileEdit) error {
+	plan := jsonPlan{Edits: []jsonEdit{}, Renames: []jsonRename{}}
+
+	for _, e := range edits {
+		if e.NewContent != nil {
+			old, err := os.ReadFile(e.Path)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", e.Path, err)
+			}
+			if d := unifiedDiff(e.Path, old, e.NewContent); d != "" {
+				plan.Edits = append(plan.Edits, jsonEdit{Path: e.Path, Diff: d})
+			}
+		}
+		if e.RenameTo != "" {
+			plan.Renames = append(plan.Renames, jsonRename{From: e.Path, To: e.RenameTo})
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(plan)
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// importDecl is a single `import` line found in a compilation unit. start
+// and end are byte offsets into the file's content delimiting just the
+// dotted path, so an edit can splice a replacement in without disturbing
+// the surrounding `import`/`static`/`.*`/`;` text.
+type importDecl struct {
+	path     string // dotted path, without the trailing ".*" if wildcard
+	static   bool
+	wildcard bool
+	start    int
+	end      int
+}
+
+// javaFile holds everything the symbol table knows about one .java source
+// file, derived from a single lexical pass.
+type javaFile struct {
+	path    string
+	pkg     string // "" for the default package
+	pkgFrom int    // byte offsets of the dotted package name, for edits
+	pkgTo   int
+	imports []importDecl
+	types   []string // top-level class/interface/enum/record names
+}
+
+// symbolTable is the whole-tree view the renamer plans against: every
+// package in use, every top-level type and the file that declares it, and
+// every compilation unit's own imports. It is built once, up front, so
+// PrepareRename can answer collision questions without re-walking the tree.
+type symbolTable struct {
+	files    []*javaFile
+	packages map[string]bool        // every package declared anywhere
+	types    map[string][]*javaFile // simple type name -> declaring files
+}
+
+// commonJDKTypes is a best-effort list of java.lang / java.util simple
+// names that are implicitly in scope and would be shadowed by a colliding
+// top-level type of the same name.
+var commonJDKTypes = map[string]bool{
+	"Object": true, "String": true, "Integer": true, "Long": true, "Short": true,
+	"Byte": true, "Boolean": true, "Character": true, "Double": true, "Float": true,
+	"Number": true, "Void": true, "Class": true, "Thread": true, "Runnable": true,
+	"Exception": true, "RuntimeException": true, "Error": true, "Throwable": true,
+	"Iterable": true, "Comparable": true, "CharSequence": true, "Math": true,
+	"System": true, "List": true, "Map": true, "Set": true, "Collection": true,
+	"ArrayList": true, "HashMap": true, "HashSet": true, "Optional": true,
+}
+
+// buildSymbolTable performs the first pass described by the renamer design:
+// walk every .java file once, lexing it to recover the package declaration,
+// the import list, and the top-level type declarations. The walk itself
+// (discovery) is sequential, but the read-and-lex work for each file - the
+// expensive part on a large tree - runs across up to jobs goroutines.
+func buildSymbolTable(root string, jobs int) (*symbolTable, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "target" || strings.HasPrefix(d.Name(), stagingDirPrefix) || strings.HasPrefix(d.Name(), backupDirPrefix) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".java") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*javaFile, len(paths))
+	if err := parallelDo(jobs, len(paths), func(i int) error {
+		content, err := os.ReadFile(paths[i])
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", paths[i], err)
+		}
+		files[i] = parseJavaFile(paths[i], string(content))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	table := &symbolTable{
+		packages: make(map[string]bool),
+		types:    make(map[string][]*javaFile),
+	}
+	for _, jf := range files {
+		table.files = append(table.files, jf)
+		if jf.pkg != "" {
+			table.packages[jf.pkg] = true
+		}
+		for _, t := range jf.types {
+			table.types[t] = append(table.types[t], jf)
+		}
+	}
+	return table, nil
+}
+
+// parseJavaFile recovers the package, imports and top-level type names from
+// a single compilation unit using the token stream from lexJava. It only
+// tracks brace depth well enough to tell a top-level declaration from a
+// nested one; it does not build a full AST.
+func parseJavaFile(path, content string) *javaFile {
+	jf := &javaFile{path: path}
+
+	tokens := lexJava(content)
+	offsets := tokenOffsets(tokens)
+
+	depth := 0
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch tok.kind {
+		case tokOther:
+			switch strings.TrimSpace(tok.text) {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+		case tokIdent:
+			switch tok.text {
+			case "package":
+				if depth == 0 {
+					name, from, to, next := readDottedName(tokens, offsets, i+1)
+					jf.pkg = name
+					jf.pkgFrom, jf.pkgTo = from, to
+					i = next - 1
+				}
+			case "import":
+				if depth == 0 {
+					imp, next := readImport(tokens, offsets, i+1)
+					jf.imports = append(jf.imports, imp)
+					i = next - 1
+				}
+			case "class", "interface", "enum", "record":
+				if depth == 0 {
+					if name := nextIdent(tokens, i+1); name != "" {
+						jf.types = append(jf.types, name)
+					}
+				}
+			}
+		}
+	}
+	return jf
+}
+
+// readDottedName reads a run of ident/'.' tokens starting at i, stopping at
+// the terminating ';'. It returns the joined name, its byte offset range in
+// the original content, and the token index just past the ';'.
+func readDottedName(tokens []javaToken, offsets []int, i int) (name string, from, to, next int) {
+	from, to = -1, -1
+	var b strings.Builder
+	for i < len(tokens) {
+		t := tokens[i]
+		if t.kind == tokIdent {
+			if from == -1 {
+				from = offsets[i]
+			}
+			b.WriteString(t.text)
+			to = offsets[i+1]
+			i++
+			continue
+		}
+		trimmed := strings.TrimSpace(t.text)
+		if trimmed == "." {
+			b.WriteString(".")
+			to = offsets[i+1]
+			i++
+			continue
+		}
+		if trimmed == ";" {
+			return b.String(), from, to, i + 1
+		}
+		if trimmed == "" {
+			i++
+			continue
+		}
+		// Unexpected token (e.g. annotations) - bail out defensively.
+		return b.String(), from, to, i + 1
+	}
+	return b.String(), from, to, i
+}
+
+// readImport parses the tail of an `import` declaration: an optional
+// `static`, a dotted path, and an optional `.*` wildcard suffix.
+func readImport(tokens []javaToken, offsets []int, i int) (importDecl, int) {
+	var decl importDecl
+	if i < len(tokens) && tokens[i].kind == tokIdent && tokens[i].text == "static" {
+		decl.static = true
+		i++
+	}
+
+	decl.start = -1
+	var b strings.Builder
+	for i < len(tokens) {
+		t := tokens[i]
+		trimmed := strings.TrimSpace(t.text)
+		switch {
+		case t.kind == tokIdent:
+			if decl.start == -1 {
+				decl.start = offsets[i]
+			}
+			b.WriteString(t.text)
+			decl.end = offsets[i+1]
+			i++
+		case trimmed == ".":
+			// Lookahead for a trailing wildcard: `.` `*` `;`.
+			if i+1 < len(tokens) && strings.TrimSpace(tokens[i+1].text) == "*" {
+				decl.wildcard = true
+				i += 2
+				continue
+			}
+			b.WriteString(".")
+			decl.end = offsets[i+1]
+			i++
+		case trimmed == ";":
+			i++
+			decl.path = b.String()
+			return decl, i
+		default:
+			i++
+		}
+	}
+	decl.path = b.String()
+	return decl, i
+}
+
+// tokenOffsets returns the byte offset of the start of each token, plus one
+// trailing entry for the end of the last token, so offsets[i] is where
+// tokens[i] begins and offsets[i+1] is where it ends.
+func tokenOffsets(tokens []javaToken) []int {
+	offsets := make([]int, len(tokens)+1)
+	for i, t := range tokens {
+		offsets[i+1] = offsets[i] + len(t.text)
+	}
+	return offsets
+}
+
+func nextIdent(tokens []javaToken, i int) string {
+	for i < len(tokens) {
+		if tokens[i].kind == tokIdent {
+			return tokens[i].text
+		}
+		if strings.TrimSpace(tokens[i].text) != "" {
+			return ""
+		}
+		i++
+	}
+	return ""
+}
+
+// renameKind selects whether oldName/newName are resolved as a package or a
+// fully-qualified type name. kindAuto asks the renamer to decide by
+// scanning the symbol table, the way `gopls rename` infers what a given
+// identifier refers to before planning edits.
+type renameKind string
+
+const (
+	kindAuto    renameKind = "auto"
+	kindPackage renameKind = "package"
+	kindType    renameKind = "type"
+)
+
+// renamer plans and applies a rename of oldName to newName across a Java
+// project, using a symbolTable built up front so conflicts are caught
+// before anything is written.
+type renamer struct {
+	root    string
+	oldName string
+	newName string
+	kind    renameKind
+	table   *symbolTable
+	jobs    int // max concurrent goroutines for Plan/Apply; always >= 1
+}
+
+func newRenamer(root, oldName, newName string, kind renameKind, jobs int) (*renamer, error) {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	table, err := buildSymbolTable(root, jobs)
+	if err != nil {
+		return nil, fmt.Errorf("building symbol table: %w", err)
+	}
+
+	if kind == kindAuto || kind == "" {
+		kind = detectKind(table, oldName)
+	}
+
+	return &renamer{root: root, oldName: oldName, newName: newName, kind: kind, table: table, jobs: jobs}, nil
+}
+
+// detectKind decides whether name refers to a package or to a top-level
+// type by checking whether the tree has a class/interface/enum/record of
+// that simple name declared in the package formed by name's leading
+// segments - e.g. for "com.foo.Bar" it looks for a type "Bar" declared in
+// package "com.foo".
+func detectKind(table *symbolTable, name string) renameKind {
+	pkg, simple := splitFQCN(name)
+	for _, f := range table.types[simple] {
+		if f.pkg == pkg {
+			return kindType
+		}
+	}
+	return kindPackage
+}
+
+// splitFQCN splits a dotted name into its package prefix and its final
+// segment, e.g. "com.foo.Bar" -> ("com.foo", "Bar"). A name with no dot is
+// treated as a default-package type, ("", name).
+func splitFQCN(name string) (pkg, simple string) {
+	parts := splitDotted(name)
+	simple = parts[len(parts)-1]
+	pkg = strings.Join(parts[:len(parts)-1], ".")
+	return pkg, simple
+}
+
+// nonStaticImportKinds reports whether jf has a direct `import
+// pkg.simple;` and/or a non-static wildcard `import pkg.*;` - the two ways
+// a file's imports can bring pkg.simple's unqualified name into scope.
+func nonStaticImportKinds(jf *javaFile, pkg, simple string) (hasDirect, hasWildcard bool) {
+	for _, imp := range jf.imports {
+		if imp.static {
+			continue
+		}
+		if imp.wildcard && imp.path == pkg {
+			hasWildcard = true
+		}
+		if !imp.wildcard && imp.path == pkg+"."+simple {
+			hasDirect = true
+		}
+	}
+	return hasDirect, hasWildcard
+}
+
+// fileImports reports whether jf's imports bring the unqualified simple
+// name of pkg.simple into scope: either a direct `import pkg.simple;` or a
+// non-static wildcard `import pkg.*;`.
+func fileImports(jf *javaFile, pkg, simple string) bool {
+	hasDirect, hasWildcard := nonStaticImportKinds(jf, pkg, simple)
+	return hasDirect || hasWildcard
+}
+
+// PrepareRename validates that newName is legal and that applying the
+// rename would not collide with an existing top-level type, package, or
+// well-known JDK class. It mirrors gopls's PrepareRename step: a pure
+// validation pass with no side effects, run before any edit is planned.
+func (r *renamer) PrepareRename() error {
+	if !isJavaPackageName(r.newName) {
+		return fmt.Errorf("%q is not a legal Java identifier or package path", r.newName)
+	}
+
+	if r.kind == kindType {
+		return r.prepareTypeRename()
+	}
+	return r.preparePackageRename()
+}
+
+func (r *renamer) preparePackageRename() error {
+	if r.table.packages[r.newName] && r.newName != r.oldName {
+		return fmt.Errorf("cannot rename %q to %q: package %q already exists in this project", r.oldName, r.newName, r.newName)
+	}
+
+	_, newSimple := splitFQCN(r.newName)
+	if commonJDKTypes[newSimple] {
+		return fmt.Errorf("cannot rename %q to %q: %q collides with a java.lang/java.util class visible by default", r.oldName, r.newName, newSimple)
+	}
+	if files, ok := r.table.types[newSimple]; ok {
+		for _, f := range files {
+			if f.pkg != r.oldName {
+				return fmt.Errorf("cannot rename %q to %q: top-level type %q already declared in %s", r.oldName, r.newName, newSimple, f.path)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *renamer) prepareTypeRename() error {
+	oldPkg, oldSimple := splitFQCN(r.oldName)
+	newPkg, newSimple := splitFQCN(r.newName)
+
+	if !r.declaringFile(oldPkg, oldSimple, r.table.files) {
+		return fmt.Errorf("no class/interface/enum/record %q found in package %q", oldSimple, oldPkg)
+	}
+
+	if commonJDKTypes[newSimple] {
+		return fmt.Errorf("cannot rename %q to %q: %q collides with a java.lang/java.util class visible by default", r.oldName, r.newName, newSimple)
+	}
+	for _, f := range r.table.types[newSimple] {
+		if f.pkg == newPkg && !(f.pkg == oldPkg && containsString(f.types, oldSimple)) {
+			return fmt.Errorf("cannot rename %q to %q: top-level type %q already declared in %s", r.oldName, r.newName, newSimple, f.path)
+		}
+	}
+
+	return nil
+}
+
+func (r *renamer) declaringFile(pkg, simple string, files []*javaFile) bool {
+	for _, f := range files {
+		if f.pkg == pkg && containsString(f.types, simple) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
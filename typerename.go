@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// dottedChain is a maximal run of `ident ('.' ident)*` found anywhere in a
+// compilation unit's token stream, e.g. a fully-qualified reference like
+// `com.foo.Bar.CONST` or a bare name like `Bar`. Comments and string/char
+// literals never produce idents, so chains never appear inside them.
+type dottedChain struct {
+	text     string
+	from, to int
+}
+
+// collectDottedChains scans the whole token stream (not just declarations)
+// so that both qualified references (`com.foo.Bar`) and unqualified ones
+// (`Bar`) can be found and rewritten.
+func collectDottedChains(content string, tokens []javaToken, offsets []int) []dottedChain {
+	var chains []dottedChain
+	i := 0
+	for i < len(tokens) {
+		if tokens[i].kind != tokIdent {
+			i++
+			continue
+		}
+		from := offsets[i]
+		to := offsets[i+1]
+		j := i + 1
+		for j+1 < len(tokens) && tokens[j].kind == tokOther && tokens[j].text == "." && tokens[j+1].kind == tokIdent {
+			to = offsets[j+2]
+			j += 2
+		}
+		chains = append(chains, dottedChain{text: content[from:to], from: from, to: to})
+		i = j
+	}
+	return chains
+}
+
+// planTypeRename handles renaming a single fully-qualified type: the
+// declaring file's declaration, filename and (if the package changed)
+// directory, every fully-qualified reference anywhere in the tree, and
+// every unqualified reference in a file where the old simple name actually
+// resolves to this type (same package, or a direct/wildcard import of it).
+func (r *renamer) planTypeRename() ([]FileEdit, error) {
+	oldPkg, oldSimple := splitFQCN(r.oldName)
+	newPkg, newSimple := splitFQCN(r.newName)
+
+	oldDir := strings.ReplaceAll(oldPkg, ".", string(filepath.Separator))
+	newDir := strings.ReplaceAll(newPkg, ".", string(filepath.Separator))
+
+	results := make([]*FileEdit, len(r.table.files))
+	err := parallelDo(r.jobs, len(r.table.files), func(i int) error {
+		jf := r.table.files[i]
+		content, err := os.ReadFile(jf.path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", jf.path, err)
+		}
+
+		resolved := jf.pkg == oldPkg || fileImports(jf, oldPkg, oldSimple)
+		declaring := jf.pkg == oldPkg && containsString(jf.types, oldSimple)
+
+		tokens := lexJava(string(content))
+		offsets := tokenOffsets(tokens)
+		chains := collectDottedChains(string(content), tokens, offsets)
+
+		newContent, changed := rewriteTypeRenameFile(content, jf, chains, oldPkg, newPkg, oldSimple, newSimple, r.oldName, r.newName, declaring, resolved)
+
+		var renameTo string
+		if declaring {
+			dir := filepath.Dir(jf.path)
+			if strings.Contains(dir, oldDir) {
+				dir = strings.Replace(dir, oldDir, newDir, 1)
+			}
+			renameTo = filepath.Join(dir, newSimple+".java")
+			if renameTo == jf.path {
+				renameTo = ""
+			}
+		}
+
+		if changed || renameTo != "" {
+			results[i] = &FileEdit{Path: jf.path, NewContent: newContent, RenameTo: renameTo}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []FileEdit
+	for _, e := range results {
+		if e != nil {
+			edits = append(edits, *e)
+		}
+	}
+	return edits, nil
+}
+
+// rewriteTypeRenameFile computes jf's full content rewrite for a type
+// rename: every dotted chain that denotes the renamed type (a
+// fully-qualified reference, or one with it as a dotted prefix, e.g. a
+// static member access, and - when resolved is true - a bare unqualified
+// reference to its simple name), plus two edits a plain chain rewrite can't
+// express as an existing span:
+//
+//   - declaring is true, jf already has a package (oldPkg != "") and the
+//     package changed: jf's own `package X;` declaration is rewritten,
+//     since its dotted chain is exactly oldPkg and so never matches any of
+//     the FQCN/simple-name cases below. Moving a type out of the default
+//     package is a pre-existing limitation (oldFQCN == oldSimple there, so
+//     the chain rewrite below can't tell the type's own declaration from a
+//     reference to it) and isn't handled here.
+//   - declaring is false, jf actually had an unqualified oldSimple chain
+//     rewritten below, the package changed, and jf relied on same-package
+//     resolution or a wildcard import (not a direct `import
+//     oldPkg.oldSimple;`, which is itself one of the FQCN chains rewritten
+//     below): a new `import newFQCN;` is inserted, since the rewritten
+//     reference would otherwise no longer resolve.
+func rewriteTypeRenameFile(content []byte, jf *javaFile, chains []dottedChain, oldPkg, newPkg, oldSimple, newSimple, oldFQCN, newFQCN string, declaring, resolved bool) ([]byte, bool) {
+	type span struct {
+		from, to int
+		text     string
+	}
+	var spans []span
+
+	if declaring && newPkg != oldPkg && jf.pkg != "" {
+		spans = append(spans, span{jf.pkgFrom, jf.pkgTo, newPkg})
+	}
+
+	rewroteUnqualifiedRef := false
+	for _, c := range chains {
+		switch {
+		case c.text == oldFQCN:
+			spans = append(spans, span{c.from, c.to, newFQCN})
+		case strings.HasPrefix(c.text, oldFQCN+"."):
+			spans = append(spans, span{c.from, c.to, newFQCN + c.text[len(oldFQCN):]})
+		case resolved && c.text == oldSimple:
+			spans = append(spans, span{c.from, c.to, newSimple})
+			rewroteUnqualifiedRef = true
+		case resolved && strings.HasPrefix(c.text, oldSimple+"."):
+			spans = append(spans, span{c.from, c.to, newSimple + c.text[len(oldSimple):]})
+			rewroteUnqualifiedRef = true
+		}
+	}
+
+	if !declaring && rewroteUnqualifiedRef && newPkg != oldPkg && needsNewImport(jf, oldPkg, oldSimple) {
+		at := importInsertionPoint(content, jf)
+		spans = append(spans, span{at, at, "\nimport " + newFQCN + ";"})
+	}
+
+	if len(spans) == 0 {
+		return content, false
+	}
+
+	sort.SliceStable(spans, func(a, b int) bool { return spans[a].from < spans[b].from })
+
+	out := append([]byte(nil), content...)
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		tail := append([]byte(nil), out[s.to:]...)
+		out = append(out[:s.from], append([]byte(s.text), tail...)...)
+	}
+	return out, true
+}
+
+// needsNewImport reports whether jf relied on same-package resolution or a
+// wildcard import to reference the renamed type unqualified, and so needs a
+// brand new import once the type's package no longer matches jf's own. A
+// direct `import oldPkg.oldSimple;` doesn't count either way - that import
+// line is itself one of the FQCN chains rewriteTypeRenameFile already
+// updates, so a file that already has one (however it also resolves the
+// type) never needs a second import added.
+func needsNewImport(jf *javaFile, oldPkg, oldSimple string) bool {
+	hasDirect, hasWildcard := nonStaticImportKinds(jf, oldPkg, oldSimple)
+	if hasDirect {
+		return false
+	}
+	return jf.pkg == oldPkg || hasWildcard
+}
+
+// importInsertionPoint finds where to splice a new import declaration into
+// content: right after the last existing import, or after the package
+// declaration if jf has none, or at the very start of the file for the
+// default package.
+func importInsertionPoint(content []byte, jf *javaFile) int {
+	if n := len(jf.imports); n > 0 {
+		return afterSemicolon(content, jf.imports[n-1].end)
+	}
+	if jf.pkg != "" {
+		return afterSemicolon(content, jf.pkgTo)
+	}
+	return 0
+}
+
+// afterSemicolon scans forward from a declaration's last known offset to
+// just past its terminating ';' - import/package declarations can't contain
+// string literals, so the first ';' found is always the real one.
+func afterSemicolon(content []byte, from int) int {
+	i := from
+	for i < len(content) && content[i] != ';' {
+		i++
+	}
+	if i < len(content) {
+		i++
+	}
+	return i
+}